@@ -0,0 +1,262 @@
+package providerutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// dynamicInventoryMeta mirrors the "_meta" key of Ansible's dynamic
+// inventory JSON contract.
+type dynamicInventoryMeta struct {
+	HostVars map[string]map[string]interface{} `json:"hostvars"`
+}
+
+// dynamicInventoryGroup mirrors the long form of a group entry in the
+// contract: {"hosts": [...], "children": [...], "vars": {...}}. The short
+// form, a bare array of hostnames, is handled separately in
+// ParseDynamicInventoryJSON.
+type dynamicInventoryGroup struct {
+	Hosts    []string               `json:"hosts"`
+	Children []string               `json:"children"`
+	Vars     map[string]interface{} `json:"vars"`
+}
+
+// ParseDynamicInventoryJSON decodes the output of `<script> --list` (or
+// `ansible-inventory --list`) into the same InventoryHost/InventoryGroup
+// shapes used for statically declared inventory.
+func ParseDynamicInventoryJSON(data []byte) ([]InventoryHost, []InventoryGroup, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to parse dynamic inventory JSON: %v", err),
+		})
+		return nil, nil, diags
+	}
+
+	meta := dynamicInventoryMeta{}
+	if metaRaw, ok := raw["_meta"]; ok {
+		if err := json.Unmarshal(metaRaw, &meta); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to parse dynamic inventory \"_meta\": %v", err),
+			})
+		}
+		delete(raw, "_meta")
+	}
+
+	groups := []InventoryGroup{}
+	hostGroups := map[string][]string{}
+	hostNames := map[string]struct{}{}
+
+	groupNames := make([]string, 0, len(raw))
+	for name := range raw {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+
+	for _, name := range groupNames {
+		groupRaw := raw[name]
+
+		var hostList []string
+		if err := json.Unmarshal(groupRaw, &hostList); err == nil {
+			for _, host := range hostList {
+				hostNames[host] = struct{}{}
+				hostGroups[host] = append(hostGroups[host], name)
+			}
+			groups = append(groups, InventoryGroup{Name: name})
+			continue
+		}
+
+		var group dynamicInventoryGroup
+		if err := json.Unmarshal(groupRaw, &group); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to parse dynamic inventory group %q: %v", name, err),
+			})
+			continue
+		}
+
+		for _, host := range group.Hosts {
+			hostNames[host] = struct{}{}
+			hostGroups[host] = append(hostGroups[host], name)
+		}
+
+		groups = append(groups, InventoryGroup{
+			Name:      name,
+			Children:  group.Children,
+			Variables: group.Vars,
+		})
+	}
+
+	// A host can be declared purely via "_meta.hostvars" with no group entry.
+	for host := range meta.HostVars {
+		hostNames[host] = struct{}{}
+	}
+
+	names := make([]string, 0, len(hostNames))
+	for name := range hostNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hosts := make([]InventoryHost, 0, len(names))
+	for _, name := range names {
+		hosts = append(hosts, InventoryHost{
+			Name:      name,
+			Groups:    hostGroups[name],
+			Variables: meta.HostVars[name],
+		})
+	}
+
+	return hosts, groups, diags
+}
+
+// LoadDynamicInventorySource runs an external inventory source and parses
+// its output. sourcePath is either an executable script implementing
+// ansible's `--list`/`--host <name>` contract, or a "plugin:" YAML file
+// handed to `ansible-inventory` instead.
+func LoadDynamicInventorySource(sourcePath string) ([]InventoryHost, []InventoryGroup, diag.Diagnostics) {
+	output, isScript, diags := runInventoryList(sourcePath)
+	if diags.HasError() {
+		return nil, nil, diags
+	}
+
+	hosts, groups, parseDiags := ParseDynamicInventoryJSON(output)
+	diags = append(diags, parseDiags...)
+	if diags.HasError() {
+		return hosts, groups, diags
+	}
+
+	if isScript {
+		diags = append(diags, fillMissingHostVars(sourcePath, hosts)...)
+	}
+
+	return hosts, groups, diags
+}
+
+// runInventoryList runs `<script> --list` for an executable inventory
+// source, or `ansible-inventory --list` for a plugin config. isScript tells
+// the caller whether the `--host <name>` fallback is available.
+func runInventoryList(sourcePath string) ([]byte, bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to stat inventory source %s: %v", sourcePath, err),
+		})
+		return nil, false, diags
+	}
+
+	isScript := info.Mode()&0111 != 0
+
+	var cmd *exec.Cmd
+	if isScript {
+		cmd = exec.Command(sourcePath, "--list")
+	} else {
+		// Not executable: treat it as an inventory plugin config.
+		cmd = exec.Command("ansible-inventory", "-i", sourcePath, "--list")
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to run inventory source %s: %v", sourcePath, err),
+		})
+		return nil, isScript, diags
+	}
+
+	return output, isScript, diags
+}
+
+// fillMissingHostVars tops up any host `--list` left with no variables by
+// calling `<script> --host <name>` for it.
+func fillMissingHostVars(sourcePath string, hosts []InventoryHost) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for i := range hosts {
+		if len(hosts[i].Variables) > 0 {
+			continue
+		}
+
+		vars, hostDiags := runInventoryHost(sourcePath, hosts[i].Name)
+		diags = append(diags, hostDiags...)
+		if len(vars) > 0 {
+			hosts[i].Variables = vars
+		}
+	}
+
+	return diags
+}
+
+// runInventoryHost runs `<script> --host <name>` and parses its JSON object
+// response into that host's variables.
+func runInventoryHost(sourcePath string, hostName string) (map[string]interface{}, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	output, err := exec.Command(sourcePath, "--host", hostName).Output()
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to run inventory source %s --host %s: %v", sourcePath, hostName, err),
+		})
+		return nil, diags
+	}
+
+	var vars map[string]interface{}
+	if err := json.Unmarshal(output, &vars); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to parse inventory source %s --host %s output: %v", sourcePath, hostName, err),
+		})
+		return nil, diags
+	}
+
+	return vars, diags
+}
+
+// MergeDynamicInventory appends dynamically-discovered hosts/groups to a set
+// of statically-declared ones. Static entries win on name collisions so HCL
+// can still override a single host/group pulled in from a dynamic source.
+func MergeDynamicInventory(
+	hosts []InventoryHost, groups []InventoryGroup,
+	dynamicHosts []InventoryHost, dynamicGroups []InventoryGroup,
+) ([]InventoryHost, []InventoryGroup) {
+	existingHosts := map[string]struct{}{}
+	for _, host := range hosts {
+		existingHosts[host.Name] = struct{}{}
+	}
+
+	existingGroups := map[string]struct{}{}
+	for _, group := range groups {
+		existingGroups[group.Name] = struct{}{}
+	}
+
+	merged := append([]InventoryHost{}, hosts...)
+	for _, host := range dynamicHosts {
+		if _, ok := existingHosts[host.Name]; ok {
+			continue
+		}
+		merged = append(merged, host)
+	}
+
+	mergedGroups := append([]InventoryGroup{}, groups...)
+	for _, group := range dynamicGroups {
+		if _, ok := existingGroups[group.Name]; ok {
+			continue
+		}
+		mergedGroups = append(mergedGroups, group)
+	}
+
+	return merged, mergedGroups
+}
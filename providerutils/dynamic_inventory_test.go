@@ -0,0 +1,113 @@
+package providerutils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+func TestParseDynamicInventoryJSONShortForm(t *testing.T) {
+	data := []byte(`{
+		"web": ["host1", "host2"],
+		"_meta": {"hostvars": {"host1": {"ansible_port": 22}}}
+	}`)
+
+	hosts, groups, diags := ParseDynamicInventoryJSON(data)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	if len(groups) != 1 || groups[0].Name != "web" {
+		t.Fatalf("expected a single \"web\" group, got %#v", groups)
+	}
+
+	names := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		names = append(names, host.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != "host1" || names[1] != "host2" {
+		t.Fatalf("expected host1/host2, got %v", names)
+	}
+
+	for _, host := range hosts {
+		if host.Name == "host1" && host.Variables["ansible_port"] != float64(22) {
+			t.Fatalf("expected host1.ansible_port == 22 from _meta.hostvars, got %#v", host.Variables)
+		}
+		if host.Name == "host2" && len(host.Variables) != 0 {
+			t.Fatalf("expected host2 to have no variables, got %#v", host.Variables)
+		}
+	}
+}
+
+func TestParseDynamicInventoryJSONLongForm(t *testing.T) {
+	data := []byte(`{
+		"web": {"hosts": ["host1"], "children": ["app"], "vars": {"env": "prod"}},
+		"app": ["host1"]
+	}`)
+
+	_, groups, diags := ParseDynamicInventoryJSON(data)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	var web *InventoryGroup
+	for i := range groups {
+		if groups[i].Name == "web" {
+			web = &groups[i]
+		}
+	}
+	if web == nil {
+		t.Fatalf("expected a \"web\" group, got %#v", groups)
+	}
+	if len(web.Children) != 1 || web.Children[0] != "app" {
+		t.Fatalf("expected web.children == [app], got %v", web.Children)
+	}
+	if web.Variables["env"] != "prod" {
+		t.Fatalf("expected web.vars.env == \"prod\", got %#v", web.Variables)
+	}
+}
+
+// TestLoadDynamicInventorySourceHostFallback exercises the legacy
+// `--list` + `--host <name>` contract end to end against a real script, so
+// it also covers runInventoryList/runInventoryHost/fillMissingHostVars.
+func TestLoadDynamicInventorySourceHostFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script fixture requires a POSIX shell")
+	}
+
+	script := filepath.Join(t.TempDir(), "inventory.sh")
+	contents := `#!/bin/sh
+if [ "$1" = "--list" ]; then
+  echo '{"web": ["host1"]}'
+elif [ "$1" = "--host" ]; then
+  if [ "$2" = "host1" ]; then
+    echo '{"ansible_port": 2222}'
+  else
+    echo '{}'
+  fi
+fi
+`
+	if err := os.WriteFile(script, []byte(contents), 0o755); err != nil {
+		t.Fatalf("fail to write script fixture: %v", err)
+	}
+
+	hosts, groups, diags := LoadDynamicInventorySource(script)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	if len(groups) != 1 || groups[0].Name != "web" {
+		t.Fatalf("expected a single \"web\" group, got %#v", groups)
+	}
+
+	if len(hosts) != 1 || hosts[0].Name != "host1" {
+		t.Fatalf("expected a single \"host1\" host, got %#v", hosts)
+	}
+
+	if hosts[0].Variables["ansible_port"] != float64(2222) {
+		t.Fatalf("expected host1.ansible_port == 2222 from the --host fallback, got %#v", hosts[0].Variables)
+	}
+}
@@ -0,0 +1,316 @@
+package providerutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// inventoryCacheMu serializes read-modify-write cycles against the cache
+// directory and its refcounts.json, since Terraform applies resources
+// concurrently within one provider process.
+var inventoryCacheMu sync.Mutex
+
+// inventoryCacheSubdir is rooted under $XDG_CACHE_HOME (or the OS default
+// cache dir when that's unset).
+const inventoryCacheSubdir = "terraform-ansible/inventories"
+
+// refcountFileName tracks how many resources currently reference each
+// cached inventory file.
+const refcountFileName = "refcounts.json"
+
+// inventoryCacheDir returns (creating if necessary) the directory generated
+// inventories are cached under.
+func inventoryCacheDir() (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to resolve cache directory: %v", err),
+			})
+			return "", diags
+		}
+		base = userCacheDir
+	}
+
+	dir := filepath.Join(base, inventoryCacheSubdir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to create cache directory %s: %v", dir, err),
+		})
+		return "", diags
+	}
+
+	return dir, diags
+}
+
+// hashInventoryContent returns the hex SHA-256 digest used as an
+// inventory's content-addressed filename stem.
+func hashInventoryContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func inventoryCacheFileName(hash string, inventoryFormat string) string {
+	if inventoryFormat == InventoryFormatYAML {
+		return hash + ".yml"
+	}
+
+	return hash + ".ini"
+}
+
+// writeCachedInventory stores content at its content-addressed path,
+// reusing the existing file when one with the same hash is already on
+// disk, and increments that file's reference count either way.
+func writeCachedInventory(content string, inventoryFormat string) (string, diag.Diagnostics) {
+	inventoryCacheMu.Lock()
+	defer inventoryCacheMu.Unlock()
+
+	dir, diags := inventoryCacheDir()
+	if diags.HasError() {
+		return "", diags
+	}
+
+	hash := hashInventoryContent(content)
+	path := filepath.Join(dir, inventoryCacheFileName(hash, inventoryFormat))
+
+	if _, err := os.Stat(path); err != nil {
+		if !os.IsNotExist(err) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to stat cached inventory %s: %v", path, err),
+			})
+			return "", diags
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to write inventory: %v", err),
+			})
+			return "", diags
+		}
+	}
+
+	diags = append(diags, incrementInventoryRefcount(dir, hash)...)
+
+	return path, diags
+}
+
+// RemoveFile releases this resource's reference to an inventory file and
+// only deletes it once no other resource references it anymore.
+func RemoveFile(filename string) diag.Diagnostics {
+	inventoryCacheMu.Lock()
+	defer inventoryCacheMu.Unlock()
+
+	dir, diags := inventoryCacheDir()
+	if diags.HasError() {
+		return diags
+	}
+
+	hash := inventoryHashFromPath(filename)
+	remaining, decrementDiags := decrementInventoryRefcount(dir, hash)
+	diags = append(diags, decrementDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	if remaining > 0 {
+		return diags
+	}
+
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to remove file %s: %v", filename, err),
+		})
+	}
+
+	return diags
+}
+
+// inventoryHashFromPath recovers the content hash from a cached inventory's
+// filename, e.g. ".../<hash>.ini" -> "<hash>".
+func inventoryHashFromPath(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// ListCachedInventories lists every inventory file currently in the cache.
+func ListCachedInventories() ([]string, diag.Diagnostics) {
+	dir, diags := inventoryCacheDir()
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to read cache dir %s: %v", dir, err),
+		})
+		return nil, diags
+	}
+
+	inventories := []string{}
+	for _, entry := range entries {
+		if entry.Name() == refcountFileName {
+			continue
+		}
+		inventories = append(inventories, filepath.Join(dir, entry.Name()))
+	}
+
+	return inventories, diags
+}
+
+// GC removes every cached inventory whose hash is neither still referenced
+// (refcount > 0) nor in activeHashes, sweeping entries orphaned by a crash
+// or an out-of-band refcount file edit.
+func GC(activeHashes []string) diag.Diagnostics {
+	inventoryCacheMu.Lock()
+	defer inventoryCacheMu.Unlock()
+
+	dir, diags := inventoryCacheDir()
+	if diags.HasError() {
+		return diags
+	}
+
+	active := map[string]struct{}{}
+	for _, hash := range activeHashes {
+		active[hash] = struct{}{}
+	}
+
+	counts, countDiags := loadInventoryRefcounts(dir)
+	diags = append(diags, countDiags...)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to read cache dir %s: %v", dir, err),
+		})
+		return diags
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == refcountFileName {
+			continue
+		}
+
+		hash := inventoryHashFromPath(entry.Name())
+		if _, ok := active[hash]; ok {
+			continue
+		}
+		if counts[hash] > 0 {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to remove unreferenced inventory %s: %v", entry.Name(), err),
+			})
+		}
+		delete(counts, hash)
+	}
+
+	diags = append(diags, saveInventoryRefcounts(dir, counts)...)
+
+	return diags
+}
+
+func loadInventoryRefcounts(dir string) (map[string]int, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	path := filepath.Join(dir, refcountFileName)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]int{}, diags
+		}
+
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to read inventory refcounts %s: %v", path, err),
+		})
+		return nil, diags
+	}
+
+	counts := map[string]int{}
+	if err := json.Unmarshal(raw, &counts); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to parse inventory refcounts %s: %v", path, err),
+		})
+		return nil, diags
+	}
+
+	return counts, diags
+}
+
+func saveInventoryRefcounts(dir string, counts map[string]int) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	raw, err := json.Marshal(counts)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to encode inventory refcounts: %v", err),
+		})
+		return diags
+	}
+
+	path := filepath.Join(dir, refcountFileName)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Fail to write inventory refcounts %s: %v", path, err),
+		})
+	}
+
+	return diags
+}
+
+func incrementInventoryRefcount(dir string, hash string) diag.Diagnostics {
+	counts, diags := loadInventoryRefcounts(dir)
+	if diags.HasError() {
+		return diags
+	}
+
+	counts[hash]++
+
+	return append(diags, saveInventoryRefcounts(dir, counts)...)
+}
+
+// decrementInventoryRefcount lowers hash's refcount by one and returns the
+// count remaining after the decrement (0 or below means nothing else
+// references it).
+func decrementInventoryRefcount(dir string, hash string) (int, diag.Diagnostics) {
+	counts, diags := loadInventoryRefcounts(dir)
+	if diags.HasError() {
+		return 0, diags
+	}
+
+	counts[hash]--
+	remaining := counts[hash]
+
+	if remaining <= 0 {
+		delete(counts, hash)
+	}
+
+	diags = append(diags, saveInventoryRefcounts(dir, counts)...)
+
+	return remaining, diags
+}
@@ -0,0 +1,88 @@
+package providerutils
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestWriteCachedInventoryDedup(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	path1, diags := writeCachedInventory("[web]\nhost1\n", InventoryFormatINI)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	path2, diags := writeCachedInventory("[web]\nhost1\n", InventoryFormatINI)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	if path1 != path2 {
+		t.Fatalf("expected identical content to resolve to the same cache path, got %q and %q", path1, path2)
+	}
+}
+
+// TestWriteCachedInventoryConcurrentRefcount checks that 50 concurrent
+// writers of identical content are all reflected in the refcount.
+func TestWriteCachedInventoryConcurrentRefcount(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+
+	const writers = 50
+	content := "[web]\nhost1\n"
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, diags := writeCachedInventory(content, InventoryFormatINI); diags.HasError() {
+				t.Errorf("unexpected error diags: %v", diags)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cacheDir, diags := inventoryCacheDir()
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	counts, diags := loadInventoryRefcounts(cacheDir)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	hash := hashInventoryContent(content)
+	if counts[hash] != writers {
+		t.Fatalf("expected refcount %d after %d concurrent writers, got %d", writers, writers, counts[hash])
+	}
+}
+
+func TestRemoveFileKeepsSharedEntryUntilLastRelease(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	path, diags := writeCachedInventory("[web]\nhost1\n", InventoryFormatINI)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+	if _, diags := writeCachedInventory("[web]\nhost1\n", InventoryFormatINI); diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	if diags := RemoveFile(path); diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected file to still exist while another reference remains, got: %v", err)
+	}
+
+	if diags := RemoveFile(path); diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be removed once its last reference was released, stat err: %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package providerutils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// ResolvedInventory is the fully computed view of an inventory graph: each
+// host's variables after walking its ancestor groups, and each group's
+// transitive (not just directly-assigned) host membership.
+type ResolvedInventory struct {
+	HostVariables map[string]map[string]interface{}
+	GroupHosts    map[string][]string
+}
+
+// ResolveInventory walks the InventoryGroup.Children DAG and computes, for
+// every host, its effective variables (ancestor group vars merged least to
+// most specific, overridden last by the host's own), and for every group,
+// its transitive set of hosts.
+func ResolveInventory(hosts []InventoryHost, groups []InventoryGroup) (ResolvedInventory, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	groupVars := map[string]map[string]interface{}{}
+	children := map[string][]string{}
+	names := map[string]struct{}{AllGroupName: {}, UngroupedGroupName: {}}
+
+	for _, group := range groups {
+		if group.Name == "" {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Inventory group is missing a name",
+			})
+			continue
+		}
+
+		names[group.Name] = struct{}{}
+		groupVars[group.Name] = group.Variables
+		children[group.Name] = append([]string{}, group.Children...)
+	}
+
+	addImplicitAllParent(children, names)
+
+	if cyclePath := detectGroupCycle(children, names); cyclePath != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Cycle detected in inventory group hierarchy: %s", strings.Join(cyclePath, " -> ")),
+		})
+		return ResolvedInventory{}, diags
+	}
+
+	parents := map[string][]string{}
+	for parent, kids := range children {
+		for _, child := range kids {
+			parents[child] = append(parents[child], parent)
+		}
+	}
+
+	directHosts := map[string][]string{}
+	hostGroupsOf := map[string][]string{}
+	hostVars := map[string]map[string]interface{}{}
+
+	for _, host := range hosts {
+		if host.Name == "" {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Inventory host is missing a name",
+			})
+			continue
+		}
+
+		hostGroups := host.Groups
+		if len(hostGroups) == 0 {
+			hostGroups = []string{UngroupedGroupName}
+		}
+
+		hostGroupsOf[host.Name] = hostGroups
+		hostVars[host.Name] = host.Variables
+
+		for _, groupName := range hostGroups {
+			names[groupName] = struct{}{}
+			directHosts[groupName] = append(directHosts[groupName], host.Name)
+		}
+	}
+
+	// Repeated after the host loop so groups named only by a host also become
+	// children of "all"; parents is rebuilt from the updated children.
+	addImplicitAllParent(children, names)
+
+	if cyclePath := detectGroupCycle(children, names); cyclePath != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  fmt.Sprintf("Cycle detected in inventory group hierarchy: %s", strings.Join(cyclePath, " -> ")),
+		})
+		return ResolvedInventory{}, diags
+	}
+
+	parents = map[string][]string{}
+	for parent, kids := range children {
+		for _, child := range kids {
+			parents[child] = append(parents[child], parent)
+		}
+	}
+
+	depth := computeGroupDepths(children, AllGroupName)
+
+	groupHostMemo := map[string][]string{}
+	groupHosts := map[string][]string{}
+	for name := range names {
+		groupHosts[name] = transitiveGroupHosts(name, children, directHosts, groupHostMemo)
+	}
+
+	hostVariables := map[string]map[string]interface{}{}
+	for hostName, directGroups := range hostGroupsOf {
+		ancestors := map[string]struct{}{AllGroupName: {}}
+		for _, groupName := range directGroups {
+			collectAncestors(groupName, parents, ancestors)
+		}
+
+		ordered := make([]string, 0, len(ancestors))
+		for name := range ancestors {
+			ordered = append(ordered, name)
+		}
+		sort.Slice(ordered, func(i, j int) bool {
+			if depth[ordered[i]] != depth[ordered[j]] {
+				return depth[ordered[i]] < depth[ordered[j]]
+			}
+			return ordered[i] < ordered[j]
+		})
+
+		merged := map[string]interface{}{}
+		for _, name := range ordered {
+			for key, value := range groupVars[name] {
+				merged[key] = value
+			}
+		}
+		for key, value := range hostVars[hostName] {
+			merged[key] = value
+		}
+
+		hostVariables[hostName] = merged
+	}
+
+	return ResolvedInventory{HostVariables: hostVariables, GroupHosts: groupHosts}, diags
+}
+
+// addImplicitAllParent makes every group with no declared parent a direct
+// child of "all", mirroring Ansible's implicit top-level grouping.
+func addImplicitAllParent(children map[string][]string, names map[string]struct{}) {
+	hasParent := map[string]struct{}{}
+	for _, kids := range children {
+		for _, child := range kids {
+			hasParent[child] = struct{}{}
+		}
+	}
+
+	var topLevel []string
+	for name := range names {
+		if name == AllGroupName {
+			continue
+		}
+		if _, ok := hasParent[name]; ok {
+			continue
+		}
+		topLevel = append(topLevel, name)
+	}
+	sort.Strings(topLevel)
+
+	children[AllGroupName] = append(children[AllGroupName], topLevel...)
+}
+
+// detectGroupCycle runs a DFS with a white/gray/black color marker over the
+// children graph. Re-entering a gray (in-progress) node means a cycle; the
+// returned path starts at the repeated node and ends back at it.
+func detectGroupCycle(children map[string][]string, names map[string]struct{}) []string {
+	const (
+		white = 0
+		gray  = 1
+		black = 2
+	)
+
+	color := map[string]int{}
+	var path []string
+	var cycle []string
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		color[name] = gray
+		path = append(path, name)
+
+		sortedChildren := append([]string{}, children[name]...)
+		sort.Strings(sortedChildren)
+
+		for _, child := range sortedChildren {
+			switch color[child] {
+			case gray:
+				start := 0
+				for i, n := range path {
+					if n == child {
+						start = i
+						break
+					}
+				}
+				cycle = append(append([]string{}, path[start:]...), child)
+				return true
+			case white:
+				if visit(child) {
+					return true
+				}
+			}
+		}
+
+		color[name] = black
+		path = path[:len(path)-1]
+		return false
+	}
+
+	var sortedNames []string
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	for _, name := range sortedNames {
+		if color[name] == white {
+			if visit(name) {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+// computeGroupDepths returns each group's longest distance from root, used
+// to order ancestor groups from least to most specific when merging vars.
+func computeGroupDepths(children map[string][]string, root string) map[string]int {
+	depth := map[string]int{root: 0}
+
+	var visit func(name string, d int)
+	visit = func(name string, d int) {
+		if current, ok := depth[name]; !ok || d > current {
+			depth[name] = d
+		}
+		for _, child := range children[name] {
+			visit(child, d+1)
+		}
+	}
+	visit(root, 0)
+
+	return depth
+}
+
+// collectAncestors walks upward from a group through its declared parents,
+// accumulating every group reached (including the group itself).
+func collectAncestors(name string, parents map[string][]string, seen map[string]struct{}) {
+	if _, ok := seen[name]; ok {
+		return
+	}
+	seen[name] = struct{}{}
+
+	for _, parent := range parents[name] {
+		collectAncestors(parent, parents, seen)
+	}
+}
+
+// transitiveGroupHosts returns the sorted, deduplicated set of hosts
+// directly or transitively (via descendant groups) assigned to a group.
+func transitiveGroupHosts(
+	name string,
+	children map[string][]string,
+	directHosts map[string][]string,
+	memo map[string][]string,
+) []string {
+	if cached, ok := memo[name]; ok {
+		return cached
+	}
+
+	set := map[string]struct{}{}
+	for _, host := range directHosts[name] {
+		set[host] = struct{}{}
+	}
+	for _, child := range children[name] {
+		for _, host := range transitiveGroupHosts(child, children, directHosts, memo) {
+			set[host] = struct{}{}
+		}
+	}
+
+	result := make([]string, 0, len(set))
+	for host := range set {
+		result = append(result, host)
+	}
+	sort.Strings(result)
+
+	memo[name] = result
+	return result
+}
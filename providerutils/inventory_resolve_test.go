@@ -0,0 +1,100 @@
+package providerutils
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResolveInventoryVariableInheritance(t *testing.T) {
+	groups := []InventoryGroup{
+		{Name: "datacenter", Variables: map[string]interface{}{"region": "us-east", "tier": "base"}},
+		{Name: "web", Children: []string{}, Variables: map[string]interface{}{"tier": "web"}},
+	}
+	hosts := []InventoryHost{
+		{Name: "host1", Groups: []string{"web", "datacenter"}, Variables: map[string]interface{}{"custom": "value"}},
+	}
+
+	resolved, diags := ResolveInventory(hosts, groups)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	vars := resolved.HostVariables["host1"]
+	if vars["region"] != "us-east" {
+		t.Fatalf("expected host1 to inherit datacenter.region, got %#v", vars)
+	}
+	if vars["custom"] != "value" {
+		t.Fatalf("expected host1's own variable to survive merge, got %#v", vars)
+	}
+}
+
+func TestResolveInventoryDetectsCycle(t *testing.T) {
+	groups := []InventoryGroup{
+		{Name: "a", Children: []string{"b"}},
+		{Name: "b", Children: []string{"a"}},
+	}
+
+	_, diags := ResolveInventory(nil, groups)
+	if !diags.HasError() {
+		t.Fatalf("expected a cycle to be reported as an error, got %v", diags)
+	}
+	if !strings.Contains(diags[0].Summary, "Cycle detected") {
+		t.Fatalf("expected a cycle diagnostic, got %q", diags[0].Summary)
+	}
+}
+
+// TestBuildPlaybookInventoryAppliesGroupInheritance is the regression test
+// for the review finding that BuildPlaybookInventory computed
+// ResolveInventory's merged variables and then discarded them, so a host in
+// a nested group never actually picked up its ancestor's vars in the
+// written file.
+func TestBuildPlaybookInventoryAppliesGroupInheritance(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	groups := []InventoryGroup{
+		{Name: "datacenter", Children: []string{"web"}, Variables: map[string]interface{}{"region": "us-east"}},
+		{Name: "web", Children: []string{}},
+	}
+	hosts := []InventoryHost{
+		{Name: "host1", Groups: []string{"web"}},
+	}
+
+	path, diags := BuildPlaybookInventory(InventoryFormatINI, "", -1, nil, hosts, groups, nil, nil)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("fail to read generated inventory: %v", err)
+	}
+
+	var hostLine string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "host1") {
+			hostLine = line
+		}
+	}
+	if !strings.Contains(hostLine, `region="us-east"`) {
+		t.Fatalf("expected host1's line to carry datacenter's inherited region var, got:\n%s", content)
+	}
+}
+
+func TestBuildPlaybookInventoryRejectsCyclicGroups(t *testing.T) {
+	groups := []InventoryGroup{
+		{Name: "a", Children: []string{"b"}},
+		{Name: "b", Children: []string{"a"}},
+	}
+	hosts := []InventoryHost{
+		{Name: "host1", Groups: []string{"a"}},
+	}
+
+	path, diags := BuildPlaybookInventory(InventoryFormatINI, "", -1, nil, hosts, groups, nil, nil)
+	if path != "" {
+		t.Fatalf("expected no inventory to be written for a cyclic group graph, got path %q", path)
+	}
+	if !diags.HasError() {
+		t.Fatalf("expected an error diagnostic for the cyclic group graph, got %v", diags)
+	}
+}
@@ -0,0 +1,171 @@
+package providerutils
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalYAMLInventory renders hosts/groups as Ansible's YAML inventory
+// format: a single "all" group whose "children" tree mirrors INI's
+// "[group:children]" sections.
+func MarshalYAMLInventory(hosts []InventoryHost, groups []InventoryGroup, hostVariables map[string]map[string]interface{}, vaultPasswords map[string]string) (string, diag.Diagnostics) {
+	graph, diags := collectInventoryGraph(hosts, groups, hostVariables, InventoryFormatYAML, vaultPasswords)
+
+	allNode := map[string]interface{}{}
+	allChildren := map[string]interface{}{}
+	for _, name := range topLevelGroups(graph) {
+		childNode, childDiags := yamlGroupNode(name, graph, map[string]struct{}{}, vaultPasswords)
+		diags = append(diags, childDiags...)
+		allChildren[name] = childNode
+	}
+	if len(allChildren) > 0 {
+		allNode["children"] = allChildren
+	}
+
+	root := map[string]interface{}{
+		"all": allNode,
+	}
+
+	out, err := yaml.Marshal(root)
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "Fail to marshal YAML inventory: " + err.Error(),
+		})
+		return "", diags
+	}
+
+	return string(out), diags
+}
+
+// topLevelGroups returns every known group name that is not listed as a
+// child of another group, i.e. the groups that hang directly off "all".
+func topLevelGroups(graph inventoryGraph) []string {
+	isChild := map[string]struct{}{}
+	for _, children := range graph.children {
+		for _, child := range children {
+			isChild[child] = struct{}{}
+		}
+	}
+
+	var names []string
+	for name := range graph.names {
+		if _, ok := isChild[name]; ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// yamlGroupNode builds the "hosts"/"vars"/"children" map for a single group,
+// recursing into its children. visited holds only the current path's
+// ancestors - a fresh copy per child - so a group reachable through more
+// than one parent is still rendered under each of them.
+func yamlGroupNode(name string, graph inventoryGraph, visited map[string]struct{}, vaultPasswords map[string]string) (map[string]interface{}, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	node := map[string]interface{}{}
+
+	if hostLines, ok := graph.hosts[name]; ok && len(hostLines) > 0 {
+		hostsNode, hostDiags := yamlHostsForGroup(name, graph, vaultPasswords)
+		diags = append(diags, hostDiags...)
+		node["hosts"] = hostsNode
+	}
+
+	if vars, ok := graph.vars[name]; ok && len(vars) > 0 {
+		varsNode, varDiags := resolveYAMLVariables(vars, vaultPasswords)
+		diags = append(diags, varDiags...)
+		node["vars"] = varsNode
+	}
+
+	if children, ok := graph.children[name]; ok && len(children) > 0 {
+		sortedChildren := append([]string{}, children...)
+		sort.Strings(sortedChildren)
+
+		childVisited := make(map[string]struct{}, len(visited)+1)
+		for seen := range visited {
+			childVisited[seen] = struct{}{}
+		}
+		childVisited[name] = struct{}{}
+
+		childNodes := map[string]interface{}{}
+		for _, child := range sortedChildren {
+			if _, seen := childVisited[child]; seen {
+				continue
+			}
+
+			childNode, childDiags := yamlGroupNode(child, graph, childVisited, vaultPasswords)
+			diags = append(diags, childDiags...)
+			childNodes[child] = childNode
+		}
+		if len(childNodes) > 0 {
+			node["children"] = childNodes
+		}
+	}
+
+	return node, diags
+}
+
+// yamlHostsForGroup builds the "hosts:" block for a group from graph.hostVars.
+func yamlHostsForGroup(groupName string, graph inventoryGraph, vaultPasswords map[string]string) (map[string]interface{}, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	names := make([]string, 0, len(graph.hostVars[groupName]))
+	for name := range graph.hostVars[groupName] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := map[string]interface{}{}
+	for _, name := range names {
+		resolved, resolveDiags := resolveYAMLVariables(graph.hostVars[groupName][name], vaultPasswords)
+		diags = append(diags, resolveDiags...)
+		result[name] = resolved
+	}
+
+	return result, diags
+}
+
+// resolveYAMLVariables copies vars, replacing any VaultValue with its
+// "!vault |" literal block form.
+func resolveYAMLVariables(vars map[string]interface{}, vaultPasswords map[string]string) (map[string]interface{}, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	resolved := make(map[string]interface{}, len(vars))
+	for key, value := range vars {
+		vault, ok := value.(VaultValue)
+		if !ok {
+			resolved[key] = value
+			continue
+		}
+
+		encrypted, err := encryptVaultValue(vault, vaultPasswords)
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to encrypt vault value for %q: %v", key, err),
+			})
+			continue
+		}
+
+		resolved[key] = vaultYAMLNode(encrypted)
+	}
+
+	return resolved, diags
+}
+
+// vaultYAMLNode wraps an armored vault string as the "!vault" literal block
+// scalar that `ansible-vault encrypt_string` produces inline in YAML files.
+func vaultYAMLNode(armored string) *yaml.Node {
+	return &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!vault",
+		Style: yaml.LiteralStyle,
+		Value: armored + "\n",
+	}
+}
@@ -0,0 +1,98 @@
+package providerutils
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMarshalYAMLInventory(t *testing.T) {
+	hosts := []InventoryHost{
+		{Name: "host1", Groups: []string{"web"}, Variables: map[string]interface{}{"ansible_port": 22}},
+	}
+	groups := []InventoryGroup{
+		{Name: "web", Variables: map[string]interface{}{"env": "prod"}},
+	}
+
+	out, diags := MarshalYAMLInventory(hosts, groups, nil, nil)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	var root map[string]interface{}
+	if err := yaml.Unmarshal([]byte(out), &root); err != nil {
+		t.Fatalf("output is not valid YAML: %v", err)
+	}
+
+	all, ok := root["all"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected top-level \"all\" map, got %#v", root["all"])
+	}
+
+	children, ok := all["children"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected \"all.children\" map, got %#v", all["children"])
+	}
+
+	web, ok := children["web"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected \"web\" group, got %#v", children["web"])
+	}
+
+	vars, ok := web["vars"].(map[string]interface{})
+	if !ok || vars["env"] != "prod" {
+		t.Fatalf("expected web.vars.env == \"prod\", got %#v", web["vars"])
+	}
+
+	webHosts, ok := web["hosts"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected \"web\" group hosts, got %#v", web["hosts"])
+	}
+
+	host1, ok := webHosts["host1"].(map[string]interface{})
+	if !ok || host1["ansible_port"] != 22 {
+		t.Fatalf("expected host1.ansible_port == 22, got %#v", webHosts["host1"])
+	}
+}
+
+// TestMarshalYAMLInventoryDiamondChildren is the regression test for the
+// review finding that yamlGroupNode's visited set was shared across the
+// whole recursive walk instead of per-path, so a group reachable through
+// two parents (a real DAG diamond) was rendered under only the first parent
+// visited and silently dropped from the rest.
+func TestMarshalYAMLInventoryDiamondChildren(t *testing.T) {
+	groups := []InventoryGroup{
+		{Name: "production", Children: []string{"web", "db"}},
+		{Name: "web", Children: []string{"shared"}},
+		{Name: "db", Children: []string{"shared"}},
+	}
+
+	out, diags := MarshalYAMLInventory(nil, groups, nil, nil)
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	var root map[string]interface{}
+	if err := yaml.Unmarshal([]byte(out), &root); err != nil {
+		t.Fatalf("output is not valid YAML: %v", err)
+	}
+
+	all := root["all"].(map[string]interface{})
+	allChildren := all["children"].(map[string]interface{})
+	production := allChildren["production"].(map[string]interface{})
+	productionChildren := production["children"].(map[string]interface{})
+
+	for _, name := range []string{"web", "db"} {
+		group, ok := productionChildren[name].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected %q under production.children, got %#v", name, productionChildren)
+		}
+		children, ok := group["children"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected %q.children, got %#v", name, group["children"])
+		}
+		if _, ok := children["shared"]; !ok {
+			t.Fatalf("expected \"shared\" under %s.children, got %#v", name, children)
+		}
+	}
+}
@@ -2,9 +2,6 @@ package providerutils
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,18 +13,28 @@ import (
 	CREATE OPTIONS
 */
 
-const DefaultHostGroup = "default"
+// AllGroupName and UngroupedGroupName are Ansible's two implicit groups.
+const (
+	AllGroupName       = "all"
+	UngroupedGroupName = "ungrouped"
+)
+
+// Supported values for the resources' "inventory_format" argument.
+const (
+	InventoryFormatINI  = "ini"
+	InventoryFormatYAML = "yaml"
+)
 
 type InventoryHost struct {
 	Name      string
 	Groups    []string
-	Variables map[string]string
+	Variables map[string]interface{}
 }
 
 type InventoryGroup struct {
 	Name      string
 	Children  []string
-	Variables map[string]string
+	Variables map[string]interface{}
 }
 
 func InterfaceToString(arr []interface{}) ([]string, diag.Diagnostics) {
@@ -95,10 +102,9 @@ func ExpandInventoryHosts(raw []interface{}) ([]InventoryHost, diag.Diagnostics)
 		groups, groupDiags := InterfaceToString(groupsRaw)
 		diags = append(diags, groupDiags...)
 
-		variables := map[string]string{}
+		variables := map[string]interface{}{}
 		if varsRaw, ok := entryMap["variables"].(map[string]interface{}); ok {
-			varDiags := mapInterfaceToStringMap(varsRaw, variables)
-			diags = append(diags, varDiags...)
+			mapInterfaceToVariables(varsRaw, variables)
 		}
 
 		hosts = append(hosts, InventoryHost{
@@ -141,10 +147,9 @@ func ExpandInventoryGroups(raw []interface{}) ([]InventoryGroup, diag.Diagnostic
 		children, childrenDiags := InterfaceToString(childrenRaw)
 		diags = append(diags, childrenDiags...)
 
-		variables := map[string]string{}
+		variables := map[string]interface{}{}
 		if varsRaw, ok := entryMap["variables"].(map[string]interface{}); ok {
-			varDiags := mapInterfaceToStringMap(varsRaw, variables)
-			diags = append(diags, varDiags...)
+			mapInterfaceToVariables(varsRaw, variables)
 		}
 
 		groups = append(groups, InventoryGroup{
@@ -158,25 +163,23 @@ func ExpandInventoryGroups(raw []interface{}) ([]InventoryGroup, diag.Diagnostic
 }
 
 func BuildPlaybookInventory(
-	inventoryDest string,
+	inventoryFormat string,
 	hostname string,
 	port int,
 	hostgroups []interface{},
 	inventoryHosts []InventoryHost,
 	inventoryGroups []InventoryGroup,
+	dynamicInventorySources []string,
+	vaultConfigs []VaultConfig,
 ) (string, diag.Diagnostics) {
 	var diags diag.Diagnostics
-	fileInfo, err := os.CreateTemp("", inventoryDest)
-	if err != nil {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  fmt.Sprintf("Fail to create inventory file: %v", err),
-		})
-	}
 
-	tempFileName := fileInfo.Name()
-	log.Printf("Inventory %s was created", fileInfo.Name())
-	defer fileInfo.Close()
+	vaultPasswords, vaultDiags := LoadVaultPasswords(vaultConfigs)
+	diags = append(diags, vaultDiags...)
+
+	if inventoryFormat == "" {
+		inventoryFormat = InventoryFormatINI
+	}
 
 	hosts := inventoryHosts
 	if len(hosts) == 0 {
@@ -184,12 +187,12 @@ func BuildPlaybookInventory(
 		diags = append(diags, hostGroupDiags...)
 
 		if len(hostGroups) == 0 {
-			hostGroups = append(hostGroups, DefaultHostGroup)
+			hostGroups = append(hostGroups, UngroupedGroupName)
 		}
 
-		hostVars := map[string]string{}
+		hostVars := map[string]interface{}{}
 		if port != -1 {
-			hostVars["ansible_port"] = strconv.Itoa(port)
+			hostVars["ansible_port"] = port
 		}
 
 		hosts = append(hosts, InventoryHost{
@@ -199,86 +202,116 @@ func BuildPlaybookInventory(
 		})
 	}
 
-	content, buildDiags := buildInventoryFileContent(hosts, inventoryGroups)
-	diags = append(diags, buildDiags...)
+	groups := inventoryGroups
+	for _, source := range dynamicInventorySources {
+		dynamicHosts, dynamicGroups, dynamicDiags := LoadDynamicInventorySource(source)
+		diags = append(diags, dynamicDiags...)
 
-	if _, err := fileInfo.WriteString(content); err != nil {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  fmt.Sprintf("Fail to write inventory: %v", err),
-		})
+		hosts, groups = MergeDynamicInventory(hosts, groups, dynamicHosts, dynamicGroups)
 	}
 
-	return tempFileName, diags
-}
+	// Catches a cyclic `children` declaration before anything is written, and
+	// gives the writers below each host's vars merged with its ancestors'.
+	resolved, resolveDiags := ResolveInventory(hosts, groups)
+	diags = append(diags, resolveDiags...)
+	if diags.HasError() {
+		return "", diags
+	}
 
-func RemoveFile(filename string) diag.Diagnostics {
-	var diags diag.Diagnostics
+	var content string
+	var buildDiags diag.Diagnostics
 
-	err := os.Remove(filename)
-	if err != nil {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  fmt.Sprintf("Fail to remove file %s: %v", filename, err),
-		})
+	switch inventoryFormat {
+	case InventoryFormatYAML:
+		content, buildDiags = MarshalYAMLInventory(hosts, groups, resolved.HostVariables, vaultPasswords)
+	default:
+		content, buildDiags = buildInventoryFileContent(hosts, groups, resolved.HostVariables, vaultPasswords)
+	}
+	diags = append(diags, buildDiags...)
+	if diags.HasError() {
+		return "", diags
 	}
 
-	return diags
-}
+	path, cacheDiags := writeCachedInventory(content, inventoryFormat)
+	diags = append(diags, cacheDiags...)
 
-func GetAllInventories(inventoryPrefix string) ([]string, diag.Diagnostics) {
-	var diags diag.Diagnostics
+	return path, diags
+}
 
-	tempDir := os.TempDir()
+// mapInterfaceToVariables copies raw HCL variable values into target as-is,
+// preserving their type instead of forcing everything through strconv.Quote.
+func mapInterfaceToVariables(input map[string]interface{}, target map[string]interface{}) {
+	for key, value := range input {
+		target[key] = value
+	}
+}
 
-	log.Printf("[TEMP DIR]: %s", tempDir)
+func buildInventoryFileContent(hosts []InventoryHost, groups []InventoryGroup, hostVariables map[string]map[string]interface{}, vaultPasswords map[string]string) (string, diag.Diagnostics) {
+	graph, diags := collectInventoryGraph(hosts, groups, hostVariables, InventoryFormatINI, vaultPasswords)
 
-	files, err := os.ReadDir(tempDir)
-	if err != nil {
-		diags = append(diags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  fmt.Sprintf("Fail to read dir %s: %v", tempDir, err),
-		})
+	var sortedGroupNames []string
+	for name := range graph.names {
+		sortedGroupNames = append(sortedGroupNames, name)
 	}
+	sort.Strings(sortedGroupNames)
 
-	inventories := []string{}
+	var builder strings.Builder
+	for _, groupName := range sortedGroupNames {
+		builder.WriteString("[" + groupName + "]\n")
 
-	for _, file := range files {
-		if strings.HasPrefix(file.Name(), inventoryPrefix) {
-			inventoryAbsPath := filepath.Join(tempDir, file.Name())
-			inventories = append(inventories, inventoryAbsPath)
+		hostLines := graph.hosts[groupName]
+		sort.Strings(hostLines)
+		for _, line := range hostLines {
+			builder.WriteString(line)
+			builder.WriteString("\n")
 		}
-	}
 
-	return inventories, diags
-}
+		builder.WriteString("\n")
 
-func mapInterfaceToStringMap(input map[string]interface{}, target map[string]string) diag.Diagnostics {
-	var diags diag.Diagnostics
+		if vars, ok := graph.vars[groupName]; ok && len(vars) > 0 {
+			varsText, varDiags := formatInventoryVariables(vars, InventoryFormatINI, vaultPasswords)
+			diags = append(diags, varDiags...)
 
-	for key, value := range input {
-		valueStr, ok := value.(string)
-		if !ok {
-			diags = append(diags, diag.Diagnostic{
-				Severity: diag.Error,
-				Summary:  fmt.Sprintf("Couldn't parse variable %s to string", key),
-			})
-			continue
+			builder.WriteString("[" + groupName + ":vars]\n")
+			builder.WriteString(varsText)
+			builder.WriteString("\n")
 		}
 
-		target[key] = valueStr
+		if children, ok := graph.children[groupName]; ok && len(children) > 0 {
+			builder.WriteString("[" + groupName + ":children]\n")
+			sortedChildren := append([]string{}, children...)
+			sort.Strings(sortedChildren)
+			for _, child := range sortedChildren {
+				builder.WriteString(child)
+				builder.WriteString("\n")
+			}
+			builder.WriteString("\n")
+		}
 	}
 
-	return diags
+	return builder.String(), diags
 }
 
-func buildInventoryFileContent(hosts []InventoryHost, groups []InventoryGroup) (string, diag.Diagnostics) {
+// inventoryGraph is the shared, group-indexed view of a set of hosts/groups
+// that both the INI and YAML writers render from.
+type inventoryGraph struct {
+	hosts    map[string][]string
+	hostVars map[string]map[string]map[string]interface{}
+	vars     map[string]map[string]interface{}
+	children map[string][]string
+	names    map[string]struct{}
+}
+
+func collectInventoryGraph(hosts []InventoryHost, groups []InventoryGroup, hostVariables map[string]map[string]interface{}, inventoryFormat string, vaultPasswords map[string]string) (inventoryGraph, diag.Diagnostics) {
 	var diags diag.Diagnostics
 
-	groupHosts := map[string][]string{}
-	groupVars := map[string]map[string]string{}
-	groupChildren := map[string][]string{}
-	groupNames := map[string]struct{}{}
+	graph := inventoryGraph{
+		hosts:    map[string][]string{},
+		hostVars: map[string]map[string]map[string]interface{}{},
+		vars:     map[string]map[string]interface{}{},
+		children: map[string][]string{},
+		names:    map[string]struct{}{},
+	}
 
 	for _, group := range groups {
 		if group.Name == "" {
@@ -289,14 +322,14 @@ func buildInventoryFileContent(hosts []InventoryHost, groups []InventoryGroup) (
 			continue
 		}
 
-		groupNames[group.Name] = struct{}{}
+		graph.names[group.Name] = struct{}{}
 
 		if len(group.Children) > 0 {
-			groupChildren[group.Name] = append([]string{}, group.Children...)
+			graph.children[group.Name] = append([]string{}, group.Children...)
 		}
 
 		if len(group.Variables) > 0 {
-			groupVars[group.Name] = group.Variables
+			graph.vars[group.Name] = group.Variables
 		}
 	}
 
@@ -311,65 +344,41 @@ func buildInventoryFileContent(hosts []InventoryHost, groups []InventoryGroup) (
 
 		hostGroups := host.Groups
 		if len(hostGroups) == 0 {
-			hostGroups = []string{DefaultHostGroup}
+			hostGroups = []string{UngroupedGroupName}
 		}
 
-		line := formatInventoryHostLine(host.Name, host.Variables)
+		vars := hostVariables[host.Name]
+		if vars == nil {
+			vars = host.Variables
+		}
+
+		line, lineDiags := formatInventoryHostLine(host.Name, vars, inventoryFormat, vaultPasswords)
+		diags = append(diags, lineDiags...)
+
 		for _, groupName := range hostGroups {
 			if groupName == "" {
 				continue
 			}
 
-			groupNames[groupName] = struct{}{}
-			groupHosts[groupName] = append(groupHosts[groupName], line)
-		}
-	}
-
-	var sortedGroupNames []string
-	for name := range groupNames {
-		sortedGroupNames = append(sortedGroupNames, name)
-	}
-	sort.Strings(sortedGroupNames)
-
-	var builder strings.Builder
-	for _, groupName := range sortedGroupNames {
-		builder.WriteString("[" + groupName + "]\n")
-
-		hostLines := groupHosts[groupName]
-		sort.Strings(hostLines)
-		for _, line := range hostLines {
-			builder.WriteString(line)
-			builder.WriteString("\n")
-		}
-
-		builder.WriteString("\n")
+			graph.names[groupName] = struct{}{}
+			graph.hosts[groupName] = append(graph.hosts[groupName], line)
 
-		if vars, ok := groupVars[groupName]; ok && len(vars) > 0 {
-			builder.WriteString("[" + groupName + ":vars]\n")
-			builder.WriteString(formatInventoryVariables(vars))
-			builder.WriteString("\n")
-		}
-
-		if children, ok := groupChildren[groupName]; ok && len(children) > 0 {
-			builder.WriteString("[" + groupName + ":children]\n")
-			sortedChildren := append([]string{}, children...)
-			sort.Strings(sortedChildren)
-			for _, child := range sortedChildren {
-				builder.WriteString(child)
-				builder.WriteString("\n")
+			if graph.hostVars[groupName] == nil {
+				graph.hostVars[groupName] = map[string]map[string]interface{}{}
 			}
-			builder.WriteString("\n")
+			graph.hostVars[groupName][host.Name] = vars
 		}
 	}
 
-	return builder.String(), diags
+	return graph, diags
 }
 
-func formatInventoryHostLine(hostname string, variables map[string]string) string {
+func formatInventoryHostLine(hostname string, variables map[string]interface{}, inventoryFormat string, vaultPasswords map[string]string) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
 	line := hostname
 
 	if len(variables) == 0 {
-		return line
+		return line, diags
 	}
 
 	keys := make([]string, 0, len(variables))
@@ -380,13 +389,18 @@ func formatInventoryHostLine(hostname string, variables map[string]string) strin
 	sort.Strings(keys)
 
 	for _, key := range keys {
-		line += fmt.Sprintf(" %s=%s", key, strconv.Quote(variables[key]))
+		value, valueDiags := formatInventoryValue(variables[key], inventoryFormat, vaultPasswords)
+		diags = append(diags, valueDiags...)
+
+		line += fmt.Sprintf(" %s=%s", key, value)
 	}
 
-	return line
+	return line, diags
 }
 
-func formatInventoryVariables(vars map[string]string) string {
+func formatInventoryVariables(vars map[string]interface{}, inventoryFormat string, vaultPasswords map[string]string) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
 	keys := make([]string, 0, len(vars))
 	for key := range vars {
 		keys = append(keys, key)
@@ -395,8 +409,53 @@ func formatInventoryVariables(vars map[string]string) string {
 
 	var builder strings.Builder
 	for _, key := range keys {
-		builder.WriteString(fmt.Sprintf("%s=%s\n", key, strconv.Quote(vars[key])))
+		value, valueDiags := formatInventoryValue(vars[key], inventoryFormat, vaultPasswords)
+		diags = append(diags, valueDiags...)
+
+		builder.WriteString(fmt.Sprintf("%s=%s\n", key, value))
 	}
 
-	return builder.String()
+	return builder.String(), diags
+}
+
+// formatInventoryValue renders a single variable value the way ansible's INI
+// parser expects: strings and fmt.Stringer values quoted, everything else
+// written bare. INI has no
+// block-scalar syntax, so a VaultValue is refused rather than corrupted.
+func formatInventoryValue(value interface{}, inventoryFormat string, vaultPasswords map[string]string) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if vault, ok := value.(VaultValue); ok {
+		if inventoryFormat == InventoryFormatINI {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  "Vaulted variables are not supported for inventory_format = \"ini\"; use inventory_format = \"yaml\" instead",
+			})
+			return "", diags
+		}
+
+		encrypted, err := encryptVaultValue(vault, vaultPasswords)
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to encrypt vault value: %v", err),
+			})
+			return "", diags
+		}
+
+		return strconv.Quote(encrypted), diags
+	}
+
+	return formatPlainInventoryValue(value), diags
+}
+
+func formatPlainInventoryValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case fmt.Stringer:
+		return strconv.Quote(v.String())
+	default:
+		return fmt.Sprintf("%v", v)
+	}
 }
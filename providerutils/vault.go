@@ -0,0 +1,227 @@
+package providerutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+// vaultKeyLength, vaultHMACLength and vaultIVLength are the lengths (in
+// bytes) of the AES key, HMAC key and IV that ansible-vault derives from the
+// vault password via PBKDF2; their sum is the PBKDF2 output length.
+const (
+	vaultSaltLength   = 32
+	vaultKeyLength    = 32
+	vaultHMACLength   = 32
+	vaultIVLength     = 16
+	vaultPBKDF2Rounds = 10000
+	vaultLineWidth    = 80
+)
+
+// VaultValue marks a host/group variable as an ansible-vault-encrypted
+// string rather than plaintext. VaultID selects which provider-level
+// `vault { id, password_file }` block supplies the encryption password.
+type VaultValue struct {
+	Plaintext string
+	VaultID   string
+}
+
+// VaultConfig is one `vault { id, password_file }` block declared on the
+// provider; it maps a vault id to the file holding its password.
+type VaultConfig struct {
+	ID           string
+	PasswordFile string
+}
+
+// LoadVaultPasswords reads each configured vault's password file into a
+// map keyed by vault id.
+func LoadVaultPasswords(configs []VaultConfig) (map[string]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	passwords := map[string]string{}
+	for _, config := range configs {
+		raw, err := os.ReadFile(config.PasswordFile)
+		if err != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Fail to read vault password file %s: %v", config.PasswordFile, err),
+			})
+			continue
+		}
+
+		passwords[config.ID] = strings.TrimRight(string(raw), "\r\n")
+	}
+
+	return passwords, diags
+}
+
+// EncryptVaultString encrypts plaintext into the armored
+// "$ANSIBLE_VAULT;1.1|1.2;AES256[;vault_id]" text ansible-vault produces:
+// PBKDF2-HMAC-SHA256 derives the AES key, HMAC key and IV; the PKCS7-padded
+// plaintext is encrypted with AES-256-CTR and authenticated with HMAC-SHA256.
+func EncryptVaultString(plaintext string, password string, vaultID string) (string, error) {
+	salt := make([]byte, vaultSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("fail to generate vault salt: %w", err)
+	}
+
+	aesKey, hmacKey, iv := deriveVaultKeys(password, salt)
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return "", fmt.Errorf("fail to init vault cipher: %w", err)
+	}
+
+	padded := pkcs7Pad([]byte(plaintext), aes.BlockSize)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(ciphertext)
+	digest := mac.Sum(nil)
+
+	body := strings.Join([]string{
+		hex.EncodeToString(salt),
+		hex.EncodeToString(digest),
+		hex.EncodeToString(ciphertext),
+	}, "\n")
+
+	vaulttext := hex.EncodeToString([]byte(body))
+
+	header := "$ANSIBLE_VAULT;1.1;AES256"
+	if vaultID != "" {
+		header = fmt.Sprintf("$ANSIBLE_VAULT;1.2;AES256;%s", vaultID)
+	}
+
+	return header + "\n" + wrapVaultText(vaulttext, vaultLineWidth), nil
+}
+
+// vaultEncryptCacheMu guards vaultEncryptCache.
+var vaultEncryptCacheMu sync.Mutex
+
+// vaultEncryptCache memoizes encryptVaultValue results keyed by
+// sha256(plaintext|vaultID|password), since EncryptVaultString draws a fresh
+// random salt on every call and would otherwise make the same VaultValue
+// hash differently (see inventory_cache.go) on every call.
+var vaultEncryptCache = map[string]string{}
+
+// encryptVaultValue encrypts a VaultValue with its vault id's configured
+// password, memoized so the same (plaintext, vaultID, password) triple
+// always returns identical armored text within this process.
+func encryptVaultValue(vault VaultValue, vaultPasswords map[string]string) (string, error) {
+	password, ok := vaultPasswords[vault.VaultID]
+	if !ok {
+		return "", fmt.Errorf("no vault password configured for vault id %q", vault.VaultID)
+	}
+
+	key := vaultEncryptCacheKey(vault.Plaintext, vault.VaultID, password)
+
+	vaultEncryptCacheMu.Lock()
+	if cached, ok := vaultEncryptCache[key]; ok {
+		vaultEncryptCacheMu.Unlock()
+		return cached, nil
+	}
+	vaultEncryptCacheMu.Unlock()
+
+	encrypted, err := EncryptVaultString(vault.Plaintext, password, vault.VaultID)
+	if err != nil {
+		return "", err
+	}
+
+	vaultEncryptCacheMu.Lock()
+	vaultEncryptCache[key] = encrypted
+	vaultEncryptCacheMu.Unlock()
+
+	return encrypted, nil
+}
+
+func vaultEncryptCacheKey(plaintext, vaultID, password string) string {
+	sum := sha256.Sum256([]byte(plaintext + "\x00" + vaultID + "\x00" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// wrapVaultText mirrors ansible-vault's output formatting: the hex blob is
+// wrapped at a fixed column width with no trailing newline on the last line.
+func wrapVaultText(text string, width int) string {
+	var lines []string
+	for len(text) > width {
+		lines = append(lines, text[:width])
+		text = text[width:]
+	}
+	lines = append(lines, text)
+
+	return strings.Join(lines, "\n")
+}
+
+// pkcs7Pad pads data to a multiple of blockSize, matching the padding
+// ansible-vault applies before AES-CTR encryption.
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padLen := blockSize - len(data)%blockSize
+	padded := make([]byte, len(data)+padLen)
+	copy(padded, data)
+	for i := len(data); i < len(padded); i++ {
+		padded[i] = byte(padLen)
+	}
+
+	return padded
+}
+
+// deriveVaultKeys runs PBKDF2-HMAC-SHA256 over password/salt to produce the
+// AES key, HMAC key and IV ansible-vault needs, in that fixed order.
+func deriveVaultKeys(password string, salt []byte) (aesKey, hmacKey, iv []byte) {
+	keyMaterial := pbkdf2SHA256([]byte(password), salt, vaultPBKDF2Rounds, vaultKeyLength+vaultHMACLength+vaultIVLength)
+
+	aesKey = keyMaterial[:vaultKeyLength]
+	hmacKey = keyMaterial[vaultKeyLength : vaultKeyLength+vaultHMACLength]
+	iv = keyMaterial[vaultKeyLength+vaultHMACLength:]
+
+	return aesKey, hmacKey, iv
+}
+
+// pbkdf2SHA256 is a minimal PBKDF2 (RFC 8018) implementation over
+// HMAC-SHA256, avoiding a dependency on golang.org/x/crypto/pbkdf2 for the
+// single call site that needs it.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	derived := make([]byte, 0, blocks*hashLen)
+	blockIndex := make([]byte, 4)
+
+	for block := 1; block <= blocks; block++ {
+		binary.BigEndian.PutUint32(blockIndex, uint32(block))
+
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(blockIndex)
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(nil)
+
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		derived = append(derived, t...)
+	}
+
+	return derived[:keyLen]
+}
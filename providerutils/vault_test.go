@@ -0,0 +1,149 @@
+package providerutils
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEncryptVaultStringFormat(t *testing.T) {
+	out, err := EncryptVaultString("hunter2", "password", "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.SplitN(out, "\n", 2)
+	if lines[0] != "$ANSIBLE_VAULT;1.2;AES256;default" {
+		t.Fatalf("unexpected header: %q", lines[0])
+	}
+
+	for _, line := range strings.Split(lines[1], "\n") {
+		if len(line) > vaultLineWidth {
+			t.Fatalf("expected lines wrapped at %d columns, got %d: %q", vaultLineWidth, len(line), line)
+		}
+	}
+}
+
+func TestEncryptVaultStringNoVaultID(t *testing.T) {
+	out, err := EncryptVaultString("hunter2", "password", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out, "$ANSIBLE_VAULT;1.1;AES256\n") {
+		t.Fatalf("expected the 1.1 header with no vault_id, got %q", out)
+	}
+}
+
+func TestDeriveVaultKeysDeterministic(t *testing.T) {
+	salt := make([]byte, vaultSaltLength)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+
+	aesKey1, hmacKey1, iv1 := deriveVaultKeys("password", salt)
+	aesKey2, hmacKey2, iv2 := deriveVaultKeys("password", salt)
+
+	if string(aesKey1) != string(aesKey2) || string(hmacKey1) != string(hmacKey2) || string(iv1) != string(iv2) {
+		t.Fatalf("expected deriveVaultKeys to be deterministic for the same password/salt")
+	}
+	if len(aesKey1) != vaultKeyLength || len(hmacKey1) != vaultHMACLength || len(iv1) != vaultIVLength {
+		t.Fatalf("unexpected key material lengths: aes=%d hmac=%d iv=%d", len(aesKey1), len(hmacKey1), len(iv1))
+	}
+}
+
+// TestEncryptVaultValueIsMemoized checks encryptVaultValue returns stable
+// ciphertext across calls despite EncryptVaultString's random salt.
+func TestEncryptVaultValueIsMemoized(t *testing.T) {
+	vault := VaultValue{Plaintext: "hunter2", VaultID: "default"}
+	passwords := map[string]string{"default": "password"}
+
+	first, err := encryptVaultValue(vault, passwords)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	second, err := encryptVaultValue(vault, passwords)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first != second {
+		t.Fatalf("expected encryptVaultValue to return identical ciphertext for the same plaintext/vault id/password across calls")
+	}
+}
+
+func TestEncryptVaultValueMissingPassword(t *testing.T) {
+	vault := VaultValue{Plaintext: "hunter2", VaultID: "missing"}
+
+	if _, err := encryptVaultValue(vault, map[string]string{}); err == nil {
+		t.Fatalf("expected an error for an unconfigured vault id")
+	}
+}
+
+// TestBuildPlaybookInventoryRejectsVaultValueForINI is the regression test
+// for the review finding that a VaultValue's multi-line armored ciphertext
+// was quoted onto a single INI "key=value" line, producing text
+// ansible-vault can't decrypt. INI output must refuse it instead.
+func TestBuildPlaybookInventoryRejectsVaultValueForINI(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	hosts := []InventoryHost{
+		{Name: "host1", Variables: map[string]interface{}{
+			"db_password": VaultValue{Plaintext: "secret", VaultID: "default"},
+		}},
+	}
+	vaultConfigs := []VaultConfig{{ID: "default", PasswordFile: writeTempVaultPasswordFile(t, "password")}}
+
+	path, diags := BuildPlaybookInventory(InventoryFormatINI, "", -1, nil, hosts, nil, nil, vaultConfigs)
+	if path != "" {
+		t.Fatalf("expected no inventory to be written for a vaulted INI variable, got path %q", path)
+	}
+	if !diags.HasError() {
+		t.Fatalf("expected an error diagnostic rejecting VaultValue for inventory_format = \"ini\"")
+	}
+}
+
+func TestMarshalYAMLInventoryVaultValue(t *testing.T) {
+	hosts := []InventoryHost{
+		{Name: "host1", Groups: []string{"web"}, Variables: map[string]interface{}{
+			"db_password": VaultValue{Plaintext: "secret", VaultID: "default"},
+		}},
+	}
+
+	out, diags := MarshalYAMLInventory(hosts, nil, nil, map[string]string{"default": "password"})
+	if diags.HasError() {
+		t.Fatalf("unexpected error diags: %v", diags)
+	}
+
+	var root map[string]interface{}
+	if err := yaml.Unmarshal([]byte(out), &root); err != nil {
+		t.Fatalf("output is not valid YAML: %v", err)
+	}
+
+	all := root["all"].(map[string]interface{})
+	children := all["children"].(map[string]interface{})
+	web := children["web"].(map[string]interface{})
+	webHosts := web["hosts"].(map[string]interface{})
+	host1 := webHosts["host1"].(map[string]interface{})
+
+	armored, ok := host1["db_password"].(string)
+	if !ok || armored == "" {
+		t.Fatalf("expected db_password to decode as an armored vault string, got %#v", host1["db_password"])
+	}
+	if armored[:len("$ANSIBLE_VAULT")] != "$ANSIBLE_VAULT" {
+		t.Fatalf("expected armored vault text, got %q", armored)
+	}
+}
+
+func writeTempVaultPasswordFile(t *testing.T, password string) string {
+	t.Helper()
+
+	path := t.TempDir() + "/vault-password"
+	if err := os.WriteFile(path, []byte(password), 0o600); err != nil {
+		t.Fatalf("fail to write vault password fixture: %v", err)
+	}
+
+	return path
+}